@@ -0,0 +1,67 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"math/bits"
+)
+
+// A PortMask is a ternary value/mask pair suitable for programming into
+// TCAM/ACL tables, XDP/eBPF match rules, or other wildcard-match hardware:
+// a port matches if port&Mask == Value&Mask.
+type PortMask struct {
+	Value, Mask uint16
+	Proto       uint8
+}
+
+// WildcardMasks decomposes p into the minimum set of value/mask pairs whose
+// union exactly covers [minPort, maxPort], using the standard
+// prefix-decomposition algorithm: repeatedly emit the largest
+// power-of-two-aligned block starting at the current lower bound that still
+// fits under maxPort, then advance past it.
+func (p *PortRange) WildcardMasks() []PortMask {
+	var masks []PortMask
+	lo, max := uint32(p.minPort), uint32(p.maxPort)
+	for lo <= max {
+		k := bits.TrailingZeros32(lo)
+		if k > 16 {
+			k = 16
+		}
+		for k > 0 && lo+(uint32(1)<<k)-1 > max {
+			k--
+		}
+		blockSize := uint32(1) << k
+		masks = append(masks, PortMask{
+			Value: uint16(lo),
+			Mask:  uint16(^(blockSize - 1)),
+			Proto: p.proto,
+		})
+		lo += blockSize
+	}
+	return masks
+}
+
+// PortRangesFromMasks expands each mask back into the range of ports it
+// covers and coalesces the results via MergeWith, undoing WildcardMasks.
+func PortRangesFromMasks(masks []PortMask) []*PortRange {
+	set := &PortRangeSet{}
+	for _, m := range masks {
+		blockSize := uint32(^m.Mask) + 1
+		lo := uint32(m.Value)
+		hi := lo + blockSize - 1
+		set.Add(&PortRange{minPort: uint16(lo), maxPort: uint16(hi), proto: m.Proto})
+	}
+	return set.Ranges()
+}
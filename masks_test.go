@@ -0,0 +1,90 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWildcardMasks(t *testing.T) {
+	for _, tc := range []struct {
+		id       string
+		p        *PortRange
+		expected []PortMask
+	}{
+		{
+			id:       "Single port",
+			p:        &PortRange{80, 80, ProtoTcp},
+			expected: []PortMask{{Value: 80, Mask: 0xffff, Proto: ProtoTcp}},
+		},
+		{
+			id: "Aligned power of two block",
+			p:  &PortRange{64, 127, ProtoTcp},
+			expected: []PortMask{
+				{Value: 64, Mask: 0xffc0, Proto: ProtoTcp},
+			},
+		},
+		{
+			id: "Unaligned range needs multiple blocks",
+			p:  &PortRange{1, 3, ProtoUdp},
+			expected: []PortMask{
+				{Value: 1, Mask: 0xffff, Proto: ProtoUdp},
+				{Value: 2, Mask: 0xfffe, Proto: ProtoUdp},
+			},
+		},
+	} {
+		t.Logf("Running WildcardMasks test case %q", tc.id)
+		got := tc.p.WildcardMasks()
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Case %q want %v, got %v", tc.id, tc.expected, got)
+		}
+	}
+}
+
+func TestPortRangesFromMasks(t *testing.T) {
+	for _, tc := range []struct {
+		id       string
+		p        *PortRange
+		expected []PortRange
+	}{
+		{
+			id:       "Single port round-trips",
+			p:        &PortRange{80, 80, ProtoTcp},
+			expected: []PortRange{{80, 80, ProtoTcp}},
+		},
+		{
+			id:       "Aligned block round-trips",
+			p:        &PortRange{64, 127, ProtoTcp},
+			expected: []PortRange{{64, 127, ProtoTcp}},
+		},
+		{
+			id:       "Unaligned range round-trips and recoalesces",
+			p:        &PortRange{1, 3, ProtoUdp},
+			expected: []PortRange{{1, 3, ProtoUdp}},
+		},
+	} {
+		t.Logf("Running PortRangesFromMasks test case %q", tc.id)
+		masks := tc.p.WildcardMasks()
+		ranges := PortRangesFromMasks(masks)
+		got := make([]PortRange, len(ranges))
+		for i, r := range ranges {
+			got[i] = *r
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Case %q want %v, got %v", tc.id, tc.expected, got)
+		}
+	}
+}
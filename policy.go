@@ -0,0 +1,168 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"errors"
+	"strings"
+)
+
+// An Action is the outcome of evaluating a Policy against a proto/port pair.
+type Action int
+
+const (
+	// Reject is the zero Action, so a Policy with no matching rule and no
+	// explicit default rejects by default.
+	Reject Action = iota
+	Accept
+)
+
+// ErrBadPolicyLine indicates a policy line isn't "accept|reject proto:port".
+var ErrBadPolicyLine = errors.New("bad policy line")
+
+// A PolicyRule pairs an Action with the PortRange it applies to.
+type PolicyRule struct {
+	Action Action
+	Range  *PortRange
+}
+
+// A Policy is an ordered list of PolicyRules, evaluated first match wins,
+// Tor exit-policy style.
+type Policy struct {
+	Rules   []PolicyRule
+	Default Action
+}
+
+// Check walks the rules in order and returns the Action of the first rule
+// whose range covers proto/port, or p.Default if none match.
+func (p *Policy) Check(proto uint8, port uint16) Action {
+	q := &PortRange{port, port, proto}
+	for _, rule := range p.Rules {
+		if rule.Range.Overlaps(q) {
+			return rule.Action
+		}
+	}
+	return p.Default
+}
+
+// ParsePolicy parses newline-separated Tor-style policy lines, e.g.
+// "accept tcp:80", "reject udp:1-1023", "accept *:*". Blank lines are
+// ignored. "*:*" expands to both the full TCP and the full UDP range.
+func ParsePolicy(text string) (*Policy, error) {
+	policy := &Policy{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rules, err := parsePolicyLine(line)
+		if err != nil {
+			return nil, err
+		}
+		policy.Rules = append(policy.Rules, rules...)
+	}
+	return policy, nil
+}
+
+func parsePolicyLine(line string) ([]PolicyRule, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return nil, ErrBadPolicyLine
+	}
+	var action Action
+	switch strings.ToLower(fields[0]) {
+	case "accept":
+		action = Accept
+	case "reject":
+		action = Reject
+	default:
+		return nil, ErrBadPolicyLine
+	}
+
+	ranges, err := parsePolicySpec(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]PolicyRule, len(ranges))
+	for i, r := range ranges {
+		rules[i] = PolicyRule{Action: action, Range: r}
+	}
+	return rules, nil
+}
+
+// parsePolicySpec parses the "proto:port" half of a policy line. "*:*" is
+// special-cased since it spans both protocols, which a single PortRange
+// can't represent; everything else reuses PortRange.UnmarshalText.
+func parsePolicySpec(spec string) ([]*PortRange, error) {
+	if spec == "*:*" {
+		return []*PortRange{
+			{minPort: 1, maxPort: maxPortNumber, proto: ProtoTcp},
+			{minPort: 1, maxPort: maxPortNumber, proto: ProtoUdp},
+		}, nil
+	}
+	r := &PortRange{}
+	if err := r.UnmarshalText([]byte(spec)); err != nil {
+		return nil, err
+	}
+	return []*PortRange{r}, nil
+}
+
+// coveredBy reports whether inner's entire range lies within outer's, using
+// Overlap to compute the intersection.
+func coveredBy(inner, outer *PortRange) bool {
+	if !inner.Overlaps(outer) {
+		return false
+	}
+	intersection := &PortRange{outer.minPort, outer.maxPort, outer.proto}
+	if err := inner.Overlap(intersection); err != nil {
+		return false
+	}
+	return *intersection == *inner
+}
+
+// Compact rewrites p.Rules in place: consecutive same-action rules whose
+// ranges overlap or are adjacent are folded into one via MergeWith, rules
+// fully shadowed by an earlier same-action rule are dropped, and rules made
+// unreachable by an earlier opposite-action superset are dropped too.
+func (p *Policy) Compact() {
+	result := make([]PolicyRule, 0, len(p.Rules))
+	for _, rule := range p.Rules {
+		shadowed := false
+		for _, prev := range result {
+			if coveredBy(rule.Range, prev.Range) {
+				shadowed = true
+				break
+			}
+		}
+		if shadowed {
+			continue
+		}
+
+		if n := len(result); n > 0 {
+			last := result[n-1]
+			if last.Action == rule.Action && mergeable(last.Range, rule.Range) {
+				merged := &PortRange{rule.Range.minPort, rule.Range.maxPort, rule.Range.proto}
+				last.Range.MergeWith(merged)
+				result[n-1].Range = merged
+				continue
+			}
+		}
+		result = append(result, PolicyRule{
+			Action: rule.Action,
+			Range:  &PortRange{rule.Range.minPort, rule.Range.maxPort, rule.Range.proto},
+		})
+	}
+	p.Rules = result
+}
@@ -0,0 +1,138 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("accept tcp:80\nreject udp:1-1023\n\naccept *:*")
+	if err != nil {
+		t.Fatalf("ParsePolicy returned unexpected error %v", err)
+	}
+	want := []PolicyRule{
+		{Accept, &PortRange{80, 80, ProtoTcp}},
+		{Reject, &PortRange{1, 1023, ProtoUdp}},
+		{Accept, &PortRange{1, 65535, ProtoTcp}},
+		{Accept, &PortRange{1, 65535, ProtoUdp}},
+	}
+	if !reflect.DeepEqual(policy.Rules, want) {
+		t.Errorf("want rules %v, got %v", want, policy.Rules)
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	for _, tc := range []struct {
+		id   string
+		text string
+	}{
+		{id: "Unknown action", text: "allow tcp:80"},
+		{id: "Missing spec", text: "accept"},
+		{id: "Bad proto", text: "accept sctp:80"},
+	} {
+		t.Logf("Running ParsePolicyErrors test case %q", tc.id)
+		if _, err := ParsePolicy(tc.text); err == nil {
+			t.Errorf("Case %q want error, got nil", tc.id)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy, err := ParsePolicy("accept tcp:80\nreject tcp:1-1023\naccept *:*")
+	if err != nil {
+		t.Fatalf("ParsePolicy returned unexpected error %v", err)
+	}
+	for _, tc := range []struct {
+		id       string
+		proto    uint8
+		port     uint16
+		expected Action
+	}{
+		{id: "Explicit accept", proto: ProtoTcp, port: 80, expected: Accept},
+		{id: "Explicit reject", proto: ProtoTcp, port: 500, expected: Reject},
+		{id: "Falls through to catch-all accept", proto: ProtoTcp, port: 8080, expected: Accept},
+		{id: "UDP falls through to catch-all accept", proto: ProtoUdp, port: 53, expected: Accept},
+	} {
+		t.Logf("Running PolicyCheck test case %q", tc.id)
+		got := policy.Check(tc.proto, tc.port)
+		if got != tc.expected {
+			t.Errorf("Case %q want %v, got %v", tc.id, tc.expected, got)
+		}
+	}
+
+	empty := &Policy{Default: Reject}
+	if got := empty.Check(ProtoTcp, 80); got != Reject {
+		t.Errorf("empty policy want Reject default, got %v", got)
+	}
+}
+
+func TestPolicyCompact(t *testing.T) {
+	for _, tc := range []struct {
+		id       string
+		rules    []PolicyRule
+		expected []PolicyRule
+	}{
+		{
+			id: "Merge adjacent same action",
+			rules: []PolicyRule{
+				{Accept, &PortRange{80, 100, ProtoTcp}},
+				{Accept, &PortRange{101, 120, ProtoTcp}},
+			},
+			expected: []PolicyRule{
+				{Accept, &PortRange{80, 120, ProtoTcp}},
+			},
+		},
+		{
+			id: "Drop rule shadowed by earlier same action",
+			rules: []PolicyRule{
+				{Accept, &PortRange{1, 65535, ProtoTcp}},
+				{Accept, &PortRange{80, 80, ProtoTcp}},
+			},
+			expected: []PolicyRule{
+				{Accept, &PortRange{1, 65535, ProtoTcp}},
+			},
+		},
+		{
+			id: "Drop rule unreachable behind opposite action superset",
+			rules: []PolicyRule{
+				{Reject, &PortRange{1, 65535, ProtoTcp}},
+				{Accept, &PortRange{80, 80, ProtoTcp}},
+			},
+			expected: []PolicyRule{
+				{Reject, &PortRange{1, 65535, ProtoTcp}},
+			},
+		},
+		{
+			id: "Different actions do not merge",
+			rules: []PolicyRule{
+				{Accept, &PortRange{80, 100, ProtoTcp}},
+				{Reject, &PortRange{101, 120, ProtoTcp}},
+			},
+			expected: []PolicyRule{
+				{Accept, &PortRange{80, 100, ProtoTcp}},
+				{Reject, &PortRange{101, 120, ProtoTcp}},
+			},
+		},
+	} {
+		t.Logf("Running PolicyCompact test case %q", tc.id)
+		p := &Policy{Rules: tc.rules}
+		p.Compact()
+		if !reflect.DeepEqual(p.Rules, tc.expected) {
+			t.Errorf("Case %q want %v, got %v", tc.id, tc.expected, p.Rules)
+		}
+	}
+}
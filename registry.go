@@ -0,0 +1,132 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ErrPortConflict is returned by Registry.Open when the requested range
+// overlaps a range already held by a different owner.
+type ErrPortConflict struct {
+	Owner interface{}
+	Range *PortRange
+}
+
+func (e *ErrPortConflict) Error() string {
+	return fmt.Sprintf("port range %s already held by %v", e.Range, e.Owner)
+}
+
+// registryEntry is a PortRange tagged with the owner that holds it.
+type registryEntry[K comparable] struct {
+	owner K
+	r     *PortRange
+}
+
+// A Registry tracks PortRanges tagged with an opaque owner key, rejecting
+// ranges that conflict with a range already held by a different owner. It
+// is the allocation-table counterpart to PortRangeSet: where a
+// PortRangeSet answers "what ports are open", a Registry also answers "who
+// opened them". The zero value is an empty, ready to use registry.
+type Registry[K comparable] struct {
+	entries []registryEntry[K]
+}
+
+// NewRegistry creates a new, empty Registry.
+func NewRegistry[K comparable]() *Registry[K] {
+	return &Registry[K]{}
+}
+
+// Open records that owner holds r. Ranges already held by owner that
+// overlap or are adjacent to r are merged into it via MergeWith. Returns
+// ErrPortConflict if r overlaps a range held by a different owner.
+func (reg *Registry[K]) Open(owner K, r *PortRange) error {
+	candidate := &PortRange{r.minPort, r.maxPort, r.proto}
+
+	idx := sort.Search(len(reg.entries), func(i int) bool {
+		return !before(reg.entries[i].r, candidate)
+	})
+
+	for i := idx; i < len(reg.entries) && reg.entries[i].r.Overlaps(candidate); i++ {
+		if reg.entries[i].owner != owner {
+			return &ErrPortConflict{Owner: reg.entries[i].owner, Range: reg.entries[i].r}
+		}
+	}
+	for i := idx - 1; i >= 0 && reg.entries[i].r.Overlaps(candidate); i-- {
+		if reg.entries[i].owner != owner {
+			return &ErrPortConflict{Owner: reg.entries[i].owner, Range: reg.entries[i].r}
+		}
+	}
+
+	reg.entries = append(reg.entries, registryEntry[K]{})
+	copy(reg.entries[idx+1:], reg.entries[idx:])
+	reg.entries[idx] = registryEntry[K]{owner: owner, r: candidate}
+
+	for idx > 0 && reg.entries[idx-1].owner == owner && mergeable(reg.entries[idx-1].r, candidate) {
+		reg.entries[idx-1].r.MergeWith(candidate)
+		reg.entries = append(reg.entries[:idx-1], reg.entries[idx:]...)
+		idx--
+	}
+	for idx < len(reg.entries)-1 && reg.entries[idx+1].owner == owner && mergeable(candidate, reg.entries[idx+1].r) {
+		reg.entries[idx+1].r.MergeWith(candidate)
+		reg.entries = append(reg.entries[:idx+1], reg.entries[idx+2:]...)
+	}
+	return nil
+}
+
+// Close releases the portion of owner's holdings covered by r, splitting a
+// stored range in two if r sits strictly inside it. Ranges held by other
+// owners are untouched. It is a no-op if owner doesn't hold any part of r.
+func (reg *Registry[K]) Close(owner K, r *PortRange) error {
+	candidate := &PortRange{r.minPort, r.maxPort, r.proto}
+
+	remaining := make([]registryEntry[K], 0, len(reg.entries))
+	for _, e := range reg.entries {
+		if e.owner != owner || !e.r.Overlaps(candidate) {
+			remaining = append(remaining, e)
+			continue
+		}
+		if e.r.minPort < candidate.minPort {
+			remaining = append(remaining, registryEntry[K]{owner, &PortRange{e.r.minPort, candidate.minPort - 1, e.r.proto}})
+		}
+		if e.r.maxPort > candidate.maxPort {
+			remaining = append(remaining, registryEntry[K]{owner, &PortRange{candidate.maxPort + 1, e.r.maxPort, e.r.proto}})
+		}
+	}
+	reg.entries = remaining
+	return nil
+}
+
+// RangesFor returns the ranges held by owner, in sorted order.
+func (reg *Registry[K]) RangesFor(owner K) []*PortRange {
+	var out []*PortRange
+	for _, e := range reg.entries {
+		if e.owner == owner {
+			out = append(out, &PortRange{e.r.minPort, e.r.maxPort, e.r.proto})
+		}
+	}
+	return out
+}
+
+// AllRanges returns every range in the registry, regardless of owner, in
+// sorted order.
+func (reg *Registry[K]) AllRanges() []*PortRange {
+	out := make([]*PortRange, len(reg.entries))
+	for i, e := range reg.entries {
+		out[i] = &PortRange{e.r.minPort, e.r.maxPort, e.r.proto}
+	}
+	return out
+}
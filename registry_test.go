@@ -0,0 +1,95 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistryOpen(t *testing.T) {
+	reg := NewRegistry[string]()
+
+	if err := reg.Open("alice", &PortRange{80, 100, ProtoTcp}); err != nil {
+		t.Fatalf("Open alice: unexpected error %v", err)
+	}
+	if err := reg.Open("bob", &PortRange{200, 210, ProtoTcp}); err != nil {
+		t.Fatalf("Open bob: unexpected error %v", err)
+	}
+
+	err := reg.Open("carol", &PortRange{90, 95, ProtoTcp})
+	if err == nil {
+		t.Fatalf("Open carol: want ErrPortConflict, got nil")
+	}
+	conflict, ok := err.(*ErrPortConflict)
+	if !ok {
+		t.Fatalf("Open carol: want *ErrPortConflict, got %T", err)
+	}
+	if conflict.Owner != "alice" {
+		t.Errorf("Open carol: want conflicting owner %q, got %v", "alice", conflict.Owner)
+	}
+
+	if err := reg.Open("alice", &PortRange{101, 120, ProtoTcp}); err != nil {
+		t.Fatalf("Open alice extension: unexpected error %v", err)
+	}
+	want := []*PortRange{{80, 120, ProtoTcp}}
+	if got := reg.RangesFor("alice"); !reflect.DeepEqual(got, want) {
+		t.Errorf("RangesFor alice want %v, got %v", want, got)
+	}
+}
+
+func TestRegistryClose(t *testing.T) {
+	reg := NewRegistry[string]()
+	reg.Open("alice", &PortRange{80, 120, ProtoTcp})
+	reg.Open("bob", &PortRange{200, 210, ProtoTcp})
+
+	if err := reg.Close("alice", &PortRange{90, 100, ProtoTcp}); err != nil {
+		t.Fatalf("Close: unexpected error %v", err)
+	}
+	want := []*PortRange{
+		{80, 89, ProtoTcp},
+		{101, 120, ProtoTcp},
+	}
+	if got := reg.RangesFor("alice"); !reflect.DeepEqual(got, want) {
+		t.Errorf("RangesFor alice after Close want %v, got %v", want, got)
+	}
+
+	wantBob := []*PortRange{{200, 210, ProtoTcp}}
+	if got := reg.RangesFor("bob"); !reflect.DeepEqual(got, wantBob) {
+		t.Errorf("RangesFor bob want %v, got %v", wantBob, got)
+	}
+
+	// Closing a range another owner never held is a no-op.
+	if err := reg.Close("carol", &PortRange{200, 210, ProtoTcp}); err != nil {
+		t.Errorf("Close no-op: unexpected error %v", err)
+	}
+	if got := reg.RangesFor("bob"); !reflect.DeepEqual(got, wantBob) {
+		t.Errorf("RangesFor bob after no-op Close want %v, got %v", wantBob, got)
+	}
+}
+
+func TestRegistryAllRanges(t *testing.T) {
+	reg := NewRegistry[string]()
+	reg.Open("alice", &PortRange{80, 80, ProtoTcp})
+	reg.Open("bob", &PortRange{443, 443, ProtoTcp})
+
+	want := []*PortRange{
+		{80, 80, ProtoTcp},
+		{443, 443, ProtoTcp},
+	}
+	if got := reg.AllRanges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AllRanges want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"sort"
+)
+
+// A PortRangeSet is a collection of port ranges, canonicalized on every
+// mutation into a sorted slice of non-overlapping, non-adjacent PortRanges
+// ordered by EntirelyLessThan (TCP before UDP, low ports before high within
+// a protocol). The zero value is an empty, ready to use set.
+type PortRangeSet struct {
+	ranges []*PortRange
+}
+
+// NewPortRangeSet creates a new, empty PortRangeSet.
+func NewPortRangeSet() *PortRangeSet {
+	return &PortRangeSet{}
+}
+
+// Ranges returns the set's canonicalized ranges in sorted order. The
+// returned slice is a copy; mutating it does not affect the set.
+func (s *PortRangeSet) Ranges() []*PortRange {
+	out := make([]*PortRange, len(s.ranges))
+	copy(out, s.ranges)
+	return out
+}
+
+// mergeable indicates whether a and b should be coalesced into one range.
+func mergeable(a, b *PortRange) bool {
+	return a.Overlaps(b) || a.Adjacent(b)
+}
+
+// before indicates whether a sorts entirely before b: every TCP range sorts
+// before every UDP range, and within a protocol ranges are ordered by
+// EntirelyLessThan.
+func before(a, b *PortRange) bool {
+	if a.proto != b.proto {
+		return a.proto < b.proto
+	}
+	return a.EntirelyLessThan(b)
+}
+
+// Add inserts p into the set, merging it with any existing range it
+// overlaps or is adjacent to so the set remains maximally coalesced. p is
+// not retained or mutated.
+func (s *PortRangeSet) Add(p *PortRange) {
+	if p == nil {
+		return
+	}
+	added := &PortRange{p.minPort, p.maxPort, p.proto}
+
+	idx := sort.Search(len(s.ranges), func(i int) bool {
+		return !before(s.ranges[i], added)
+	})
+	s.ranges = append(s.ranges, nil)
+	copy(s.ranges[idx+1:], s.ranges[idx:])
+	s.ranges[idx] = added
+
+	for idx > 0 && mergeable(s.ranges[idx-1], added) {
+		s.ranges[idx-1].MergeWith(added)
+		s.ranges = append(s.ranges[:idx-1], s.ranges[idx:]...)
+		idx--
+	}
+	for idx < len(s.ranges)-1 && mergeable(added, s.ranges[idx+1]) {
+		s.ranges[idx+1].MergeWith(added)
+		s.ranges = append(s.ranges[:idx+1], s.ranges[idx+2:]...)
+	}
+}
+
+// Remove removes p from the set, splitting any stored range that strictly
+// contains it into the leftover piece(s) on either side. p is not retained
+// or mutated.
+func (s *PortRangeSet) Remove(p *PortRange) {
+	if p == nil {
+		return
+	}
+	remaining := make([]*PortRange, 0, len(s.ranges))
+	for _, r := range s.ranges {
+		if !r.Overlaps(p) {
+			remaining = append(remaining, r)
+			continue
+		}
+		if r.minPort < p.minPort {
+			remaining = append(remaining, &PortRange{r.minPort, p.minPort - 1, r.proto})
+		}
+		if r.maxPort > p.maxPort {
+			remaining = append(remaining, &PortRange{p.maxPort + 1, r.maxPort, r.proto})
+		}
+	}
+	s.ranges = remaining
+}
+
+// Union adds every range in o to s.
+func (s *PortRangeSet) Union(o *PortRangeSet) {
+	for _, r := range o.ranges {
+		s.Add(r)
+	}
+}
+
+// Subtract removes every range in o from s.
+func (s *PortRangeSet) Subtract(o *PortRangeSet) {
+	for _, r := range o.ranges {
+		s.Remove(r)
+	}
+}
+
+// Intersect reduces s to the ports present in both s and o.
+func (s *PortRangeSet) Intersect(o *PortRangeSet) {
+	result := &PortRangeSet{}
+	i, j := 0, 0
+	for i < len(s.ranges) && j < len(o.ranges) {
+		a, b := s.ranges[i], o.ranges[j]
+		if a.Overlaps(b) {
+			overlap := &PortRange{a.minPort, a.maxPort, a.proto}
+			b.Overlap(overlap)
+			result.Add(overlap)
+			if a.maxPort <= b.maxPort {
+				i++
+			} else {
+				j++
+			}
+			continue
+		}
+		if before(a, b) {
+			i++
+		} else {
+			j++
+		}
+	}
+	s.ranges = result.ranges
+}
+
+// Contains indicates whether the set includes the given proto/port pair.
+func (s *PortRangeSet) Contains(proto uint8, port uint16) bool {
+	q := &PortRange{port, port, proto}
+	idx := sort.Search(len(s.ranges), func(i int) bool {
+		return !before(s.ranges[i], q)
+	})
+	return idx < len(s.ranges) && s.ranges[idx].Overlaps(q)
+}
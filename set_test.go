@@ -0,0 +1,168 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"reflect"
+	"testing"
+)
+
+func rangesOf(s *PortRangeSet) []PortRange {
+	out := make([]PortRange, 0, len(s.ranges))
+	for _, r := range s.Ranges() {
+		out = append(out, *r)
+	}
+	return out
+}
+
+func TestPortRangeSetAdd(t *testing.T) {
+	for _, tc := range []struct {
+		id       string
+		adds     []*PortRange
+		expected []PortRange
+	}{
+		{
+			id: "Disjoint ranges stay separate",
+			adds: []*PortRange{
+				{80, 80, ProtoTcp},
+				{443, 443, ProtoTcp},
+			},
+			expected: []PortRange{
+				{80, 80, ProtoTcp},
+				{443, 443, ProtoTcp},
+			},
+		},
+		{
+			id: "Overlapping ranges coalesce",
+			adds: []*PortRange{
+				{80, 100, ProtoTcp},
+				{90, 120, ProtoTcp},
+			},
+			expected: []PortRange{
+				{80, 120, ProtoTcp},
+			},
+		},
+		{
+			id: "Adjacent ranges coalesce",
+			adds: []*PortRange{
+				{80, 100, ProtoTcp},
+				{101, 120, ProtoTcp},
+			},
+			expected: []PortRange{
+				{80, 120, ProtoTcp},
+			},
+		},
+		{
+			id: "Gap fill merges both neighbors",
+			adds: []*PortRange{
+				{80, 90, ProtoTcp},
+				{101, 110, ProtoTcp},
+				{91, 100, ProtoTcp},
+			},
+			expected: []PortRange{
+				{80, 110, ProtoTcp},
+			},
+		},
+		{
+			id: "Protocols segregated",
+			adds: []*PortRange{
+				{80, 80, ProtoUdp},
+				{80, 80, ProtoTcp},
+			},
+			expected: []PortRange{
+				{80, 80, ProtoTcp},
+				{80, 80, ProtoUdp},
+			},
+		},
+	} {
+		t.Logf("Running PortRangeSetAdd test case %q", tc.id)
+		s := NewPortRangeSet()
+		for _, p := range tc.adds {
+			s.Add(p)
+		}
+		got := rangesOf(s)
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Case %q want %v, got %v", tc.id, tc.expected, got)
+		}
+	}
+}
+
+func TestPortRangeSetRemove(t *testing.T) {
+	s := NewPortRangeSet()
+	s.Add(&PortRange{80, 120, ProtoTcp})
+
+	s.Remove(&PortRange{90, 100, ProtoTcp})
+	want := []PortRange{
+		{80, 89, ProtoTcp},
+		{101, 120, ProtoTcp},
+	}
+	if got := rangesOf(s); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after splitting remove, want %v, got %v", want, got)
+	}
+
+	s.Remove(&PortRange{80, 89, ProtoTcp})
+	want = []PortRange{
+		{101, 120, ProtoTcp},
+	}
+	if got := rangesOf(s); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after exact remove, want %v, got %v", want, got)
+	}
+}
+
+func TestPortRangeSetUnionIntersectSubtract(t *testing.T) {
+	a := NewPortRangeSet()
+	a.Add(&PortRange{80, 100, ProtoTcp})
+	b := NewPortRangeSet()
+	b.Add(&PortRange{90, 120, ProtoTcp})
+
+	union := NewPortRangeSet()
+	union.Union(a)
+	union.Union(b)
+	wantUnion := []PortRange{{80, 120, ProtoTcp}}
+	if got := rangesOf(union); !reflect.DeepEqual(got, wantUnion) {
+		t.Errorf("Union want %v, got %v", wantUnion, got)
+	}
+
+	intersect := NewPortRangeSet()
+	intersect.Add(&PortRange{80, 100, ProtoTcp})
+	intersect.Intersect(b)
+	wantIntersect := []PortRange{{90, 100, ProtoTcp}}
+	if got := rangesOf(intersect); !reflect.DeepEqual(got, wantIntersect) {
+		t.Errorf("Intersect want %v, got %v", wantIntersect, got)
+	}
+
+	subtract := NewPortRangeSet()
+	subtract.Add(&PortRange{80, 100, ProtoTcp})
+	subtract.Subtract(b)
+	wantSubtract := []PortRange{{80, 89, ProtoTcp}}
+	if got := rangesOf(subtract); !reflect.DeepEqual(got, wantSubtract) {
+		t.Errorf("Subtract want %v, got %v", wantSubtract, got)
+	}
+}
+
+func TestPortRangeSetContains(t *testing.T) {
+	s := NewPortRangeSet()
+	s.Add(&PortRange{80, 100, ProtoTcp})
+
+	if !s.Contains(ProtoTcp, 90) {
+		t.Errorf("want Contains(tcp, 90) true")
+	}
+	if s.Contains(ProtoTcp, 101) {
+		t.Errorf("want Contains(tcp, 101) false")
+	}
+	if s.Contains(ProtoUdp, 90) {
+		t.Errorf("want Contains(udp, 90) false")
+	}
+}
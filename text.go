@@ -0,0 +1,139 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultProto is the protocol assumed when parsing a port spec with no
+// "proto:" prefix, e.g. "80". It is ProtoInvalid by default, which causes
+// UnmarshalText to return ErrBadProto for bare port specs.
+var DefaultProto uint8 = ProtoInvalid
+
+// ErrEmptyField indicates that a "proto:port" spec had an empty protocol or
+// port piece, e.g. "tcp:" or ":80".
+var ErrEmptyField = fmt.Errorf("empty field in port range spec")
+
+const maxPortNumber = 65535
+
+// protoName returns the canonical lowercase text for proto, or ErrBadProto if
+// it isn't ProtoTcp or ProtoUdp.
+func protoName(proto uint8) (string, error) {
+	switch proto {
+	case ProtoTcp:
+		return "tcp", nil
+	case ProtoUdp:
+		return "udp", nil
+	}
+	return "", ErrBadProto
+}
+
+// parseProtoName parses a protocol name case-insensitively. Returns
+// ErrBadProto if s isn't "tcp" or "udp".
+func parseProtoName(s string) (uint8, error) {
+	switch strings.ToLower(s) {
+	case "tcp":
+		return ProtoTcp, nil
+	case "udp":
+		return ProtoUdp, nil
+	}
+	return ProtoInvalid, ErrBadProto
+}
+
+// String renders p in canonical "proto:port" form, e.g. "tcp:80" or
+// "udp:5000-6000". min==max collapses to a single port number.
+func (p *PortRange) String() string {
+	name, err := protoName(p.proto)
+	if err != nil {
+		name = fmt.Sprintf("proto%d", p.proto)
+	}
+	if p.minPort == p.maxPort {
+		return fmt.Sprintf("%s:%d", name, p.minPort)
+	}
+	return fmt.Sprintf("%s:%d-%d", name, p.minPort, p.maxPort)
+}
+
+// MarshalText implements encoding.TextMarshaler, rendering p in the same
+// canonical "proto:port" form as String.
+func (p *PortRange) MarshalText() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts
+// "proto:port", "proto:min-max", and "proto:*" (meaning the full 1-65535
+// range), case-insensitively on proto. A bare port spec with no "proto:"
+// prefix, e.g. "80", uses DefaultProto and returns ErrBadProto if that
+// hasn't been set. Returns ErrEmptyField if the protocol or port piece is
+// empty, and ErrBadRange for port 0 or an inverted range, matching
+// Validate's semantics.
+func (p *PortRange) UnmarshalText(text []byte) error {
+	s := string(text)
+	proto := DefaultProto
+	portSpec := s
+	if idx := strings.IndexByte(s, ':'); idx >= 0 {
+		protoStr, rest := s[:idx], s[idx+1:]
+		if protoStr == "" || rest == "" {
+			return ErrEmptyField
+		}
+		parsed, err := parseProtoName(protoStr)
+		if err != nil {
+			return err
+		}
+		proto = parsed
+		portSpec = rest
+	} else if portSpec == "" {
+		return ErrEmptyField
+	}
+	if proto == ProtoInvalid {
+		return ErrBadProto
+	}
+
+	var minPort, maxPort uint16
+	if portSpec == "*" {
+		minPort, maxPort = 1, maxPortNumber
+	} else if lo, hi, ok := strings.Cut(portSpec, "-"); ok {
+		if lo == "" || hi == "" {
+			return ErrEmptyField
+		}
+		min64, err := strconv.ParseUint(lo, 10, 16)
+		if err != nil {
+			return ErrBadRange
+		}
+		max64, err := strconv.ParseUint(hi, 10, 16)
+		if err != nil {
+			return ErrBadRange
+		}
+		minPort, maxPort = uint16(min64), uint16(max64)
+	} else {
+		port64, err := strconv.ParseUint(portSpec, 10, 16)
+		if err != nil {
+			return ErrBadRange
+		}
+		minPort, maxPort = uint16(port64), uint16(port64)
+	}
+
+	candidate := &PortRange{minPort: minPort, maxPort: maxPort, proto: proto}
+	if err := candidate.Validate(); err != nil {
+		return err
+	}
+	*p = *candidate
+	return nil
+}
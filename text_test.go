@@ -0,0 +1,145 @@
+// Copyright 2015 Jason Mansfield
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portrange
+
+import (
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	for _, tc := range []struct {
+		id       string
+		p        *PortRange
+		expected string
+	}{
+		{
+			id:       "Single TCP port",
+			p:        &PortRange{80, 80, ProtoTcp},
+			expected: "tcp:80",
+		},
+		{
+			id:       "UDP range",
+			p:        &PortRange{5000, 6000, ProtoUdp},
+			expected: "udp:5000-6000",
+		},
+	} {
+		t.Logf("Running String test case %q", tc.id)
+		got := tc.p.String()
+		if got != tc.expected {
+			t.Errorf("Case %q want %q, got %q", tc.id, tc.expected, got)
+		}
+	}
+}
+
+func TestUnmarshalText(t *testing.T) {
+	for _, tc := range []struct {
+		id       string
+		text     string
+		proto    uint8
+		expected *PortRange
+		err      error
+	}{
+		{
+			id:       "Single TCP port",
+			text:     "tcp:80",
+			expected: &PortRange{80, 80, ProtoTcp},
+		},
+		{
+			id:       "UDP range",
+			text:     "udp:5000-6000",
+			expected: &PortRange{5000, 6000, ProtoUdp},
+		},
+		{
+			id:       "Case insensitive proto",
+			text:     "TCP:80",
+			expected: &PortRange{80, 80, ProtoTcp},
+		},
+		{
+			id:       "Wildcard port spec",
+			text:     "tcp:*",
+			expected: &PortRange{1, 65535, ProtoTcp},
+		},
+		{
+			id:       "Bare port with default proto",
+			text:     "80",
+			proto:    ProtoTcp,
+			expected: &PortRange{80, 80, ProtoTcp},
+		},
+		{
+			id:   "Bare port without default proto",
+			text: "80",
+			err:  ErrBadProto,
+		},
+		{
+			id:   "Unknown protocol",
+			text: "sctp:80",
+			err:  ErrBadProto,
+		},
+		{
+			id:   "Empty protocol",
+			text: ":80",
+			err:  ErrEmptyField,
+		},
+		{
+			id:   "Empty port",
+			text: "tcp:",
+			err:  ErrEmptyField,
+		},
+		{
+			id:   "Empty range bound",
+			text: "tcp:80-",
+			err:  ErrEmptyField,
+		},
+		{
+			id:   "Port 0",
+			text: "tcp:0",
+			err:  ErrBadRange,
+		},
+		{
+			id:   "Inverted range",
+			text: "tcp:20-10",
+			err:  ErrBadRange,
+		},
+	} {
+		t.Logf("Running UnmarshalText test case %q", tc.id)
+		old := DefaultProto
+		DefaultProto = tc.proto
+		p := &PortRange{}
+		err := p.UnmarshalText([]byte(tc.text))
+		DefaultProto = old
+		if err != tc.err {
+			t.Errorf("Case %q err want %v, got %v", tc.id, tc.err, err)
+		}
+		if tc.expected != nil && *p != *tc.expected {
+			t.Errorf("Case %q want %v, got %v", tc.id, tc.expected, p)
+		}
+	}
+}
+
+func TestMarshalText(t *testing.T) {
+	p := &PortRange{minPort: 80, maxPort: 80, proto: ProtoTcp}
+	text, err := p.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText returned unexpected error %v", err)
+	}
+	if string(text) != "tcp:80" {
+		t.Errorf("want %q, got %q", "tcp:80", string(text))
+	}
+
+	invalid := &PortRange{minPort: 0, maxPort: 80, proto: ProtoTcp}
+	if _, err := invalid.MarshalText(); err != ErrBadRange {
+		t.Errorf("want ErrBadRange, got %v", err)
+	}
+}